@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"gopkg.in/yaml.v3"
+)
+
+// dpapiPrefix marks a config value as a base64-encoded DPAPI blob rather
+// than plaintext, e.g. ConsolePassword: "dpapi:AQAAANCMnd8BFdERjHoAwE/..."
+const dpapiPrefix = "dpapi:"
+
+// fileConfig is the on-disk shape of -config, loaded from YAML or JSON.
+// Field names match InstallConfig; secrets may be plaintext, an
+// ESET_*-style env var reference is handled separately via env fallback.
+type fileConfig struct {
+	ConsolePassword string `yaml:"ConsolePassword" json:"ConsolePassword"`
+	DBPassword      string `yaml:"DBPassword" json:"DBPassword"`
+	InstallPath     string `yaml:"InstallPath" json:"InstallPath"`
+	LogPath         string `yaml:"LogPath" json:"LogPath"`
+	InstallerURL    string `yaml:"InstallerURL" json:"InstallerURL"`
+	ExpectedSHA256  string `yaml:"ExpectedSHA256" json:"ExpectedSHA256"`
+	SkipDownload    bool   `yaml:"SkipDownload" json:"SkipDownload"`
+	AutoFirewall    bool   `yaml:"AutoFirewall" json:"AutoFirewall"`
+}
+
+// LoadConfigFile reads path as YAML or JSON (chosen by extension, defaulting
+// to YAML) and resolves any DPAPI-protected or environment-sourced secrets
+// into an InstallConfig.
+func LoadConfigFile(path string) (*InstallConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var fc fileConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(raw, &fc); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON config: %w", err)
+		}
+	} else {
+		if err := yaml.Unmarshal(raw, &fc); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+		}
+	}
+
+	consolePassword, err := resolveSecret(fc.ConsolePassword, "ESET_CONSOLE_PASSWORD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ConsolePassword: %w", err)
+	}
+
+	dbPassword, err := resolveSecret(fc.DBPassword, "ESET_DB_PASSWORD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve DBPassword: %w", err)
+	}
+
+	return &InstallConfig{
+		ConsolePassword: consolePassword,
+		DBPassword:      dbPassword,
+		InstallPath:     fc.InstallPath,
+		LogPath:         fc.LogPath,
+		InstallerURL:    fc.InstallerURL,
+		ExpectedSHA256:  fc.ExpectedSHA256,
+		SkipDownload:    fc.SkipDownload,
+		AutoFirewall:    fc.AutoFirewall,
+	}, nil
+}
+
+// resolveSecret turns a raw config value into a plaintext secret. An empty
+// value falls back to envVar. A value prefixed with dpapiPrefix is treated
+// as a base64-encoded blob produced by CryptProtectData and is decrypted
+// with the current user's DPAPI key. Anything else is used as-is.
+func resolveSecret(raw, envVar string) (string, error) {
+	if raw == "" {
+		return os.Getenv(envVar), nil
+	}
+
+	if strings.HasPrefix(raw, dpapiPrefix) {
+		blob, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(raw, dpapiPrefix))
+		if err != nil {
+			return "", fmt.Errorf("invalid DPAPI blob: %w", err)
+		}
+		plain, err := unprotectDPAPI(blob)
+		if err != nil {
+			return "", fmt.Errorf("failed to unprotect DPAPI blob: %w", err)
+		}
+		return string(plain), nil
+	}
+
+	return raw, nil
+}
+
+// dataBlob mirrors the Win32 CRYPT_INTEGER_BLOB / DATA_BLOB struct used by
+// CryptProtectData and CryptUnprotectData.
+type dataBlob struct {
+	cbData uint32
+	pbData *byte
+}
+
+var (
+	crypt32DPAPIDLL        = windows.NewLazySystemDLL("crypt32.dll")
+	procCryptUnprotectData = crypt32DPAPIDLL.NewProc("CryptUnprotectData")
+	kernel32DPAPIDLL       = windows.NewLazySystemDLL("kernel32.dll")
+	procLocalFree          = kernel32DPAPIDLL.NewProc("LocalFree")
+)
+
+// unprotectDPAPI decrypts a blob previously produced by CryptProtectData
+// under the current user/machine DPAPI key, so secrets can be stored
+// encrypted on disk instead of in plaintext config files.
+func unprotectDPAPI(blob []byte) ([]byte, error) {
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("empty DPAPI blob")
+	}
+
+	in := dataBlob{
+		cbData: uint32(len(blob)),
+		pbData: &blob[0],
+	}
+	var out dataBlob
+
+	ret, _, err := procCryptUnprotectData.Call(
+		uintptr(unsafe.Pointer(&in)),
+		0, // ppszDataDescr
+		0, // pOptionalEntropy
+		0, // pvReserved
+		0, // pPromptStruct
+		0, // dwFlags
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if ret == 0 {
+		return nil, fmt.Errorf("CryptUnprotectData failed: %w", err)
+	}
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(out.pbData)))
+
+	// Copy into a Go-owned slice before the deferred LocalFree runs; the
+	// slice header above still points at memory CryptUnprotectData
+	// allocated, which LocalFree frees as soon as this function returns.
+	plain := append([]byte(nil), unsafe.Slice(out.pbData, out.cbData)...)
+	return plain, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// envElevateParentPID and envElevatePipe are set by EnsureElevated on the
+// relaunched elevated child, not passed as CLI flags: main() dispatches
+// subcommands by testing os.Args[1] literally before any flag parsing, so
+// prepending flags to the relaunch args would shift that check and silently
+// fall through to the wrong subcommand (or none at all).
+const (
+	envElevateParentPID = "EPOP_ELEVATE_PARENT_PID"
+	envElevatePipe      = "EPOP_ELEVATE_PIPE"
+)
+
+// CheckAdminPrivileges reports whether the current process token is
+// elevated, via GetTokenInformation(TokenElevation) rather than a
+// side-effectful probe like opening a physical drive.
+func CheckAdminPrivileges() bool {
+	token, err := windows.OpenCurrentProcessToken()
+	if err != nil {
+		return false
+	}
+	defer token.Close()
+
+	return token.IsElevated()
+}
+
+// EnsureElevated checks the current process's privilege level and, if it is
+// not elevated, relaunches it via ShellExecuteEx with lpVerb="runas",
+// mirrors the elevated child's console output back to this process over a
+// named pipe, waits for it to exit, and then exits this process with the
+// same code. It is a no-op when already elevated.
+func EnsureElevated(logger *Logger) error {
+	if CheckAdminPrivileges() {
+		return nil
+	}
+
+	logger.Warn("Not running elevated; requesting UAC elevation...")
+
+	pipeName := fmt.Sprintf(`\\.\pipe\eset-epop-%d`, os.Getpid())
+	pipeServer, err := newPipeServer(pipeName)
+	if err != nil {
+		return fmt.Errorf("failed to create mirror pipe: %w", err)
+	}
+	defer pipeServer.close()
+
+	go pipeServer.mirrorTo(os.Stdout)
+
+	os.Setenv(envElevateParentPID, strconv.Itoa(os.Getpid()))
+	os.Setenv(envElevatePipe, pipeName)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate running executable: %w", err)
+	}
+
+	hProcess, err := shellExecuteRunAs(exe, strings.Join(quoteArgs(os.Args[1:]), " "))
+	if err != nil {
+		return fmt.Errorf("failed to relaunch elevated: %w", err)
+	}
+	defer windows.CloseHandle(hProcess)
+
+	windows.WaitForSingleObject(hProcess, windows.INFINITE)
+
+	var exitCode uint32
+	windows.GetExitCodeProcess(hProcess, &exitCode)
+
+	os.Exit(int(exitCode))
+	return nil
+}
+
+// quoteArgs wraps any argument containing whitespace in double quotes, as
+// required by the Win32 command line parser ShellExecuteEx hands parameters
+// to.
+func quoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if strings.ContainsAny(a, " \t") {
+			quoted[i] = `"` + a + `"`
+		} else {
+			quoted[i] = a
+		}
+	}
+	return quoted
+}
+
+// shell32.dll!ShellExecuteExW is not wrapped by golang.org/x/sys/windows, so
+// it is declared by hand, the same way wintrust.dll is in authenticode.go.
+var (
+	shell32DLL          = windows.NewLazySystemDLL("shell32.dll")
+	procShellExecuteExW = shell32DLL.NewProc("ShellExecuteExW")
+)
+
+const (
+	seeMaskNoCloseProcess = 0x00000040
+	swShowNormal          = 1
+)
+
+// shellExecuteInfo mirrors SHELLEXECUTEINFOW.
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         windows.Handle
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     windows.Handle
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    windows.Handle
+	dwHotKey     uint32
+	hIconOrMon   windows.Handle
+	hProcess     windows.Handle
+}
+
+// shellExecuteRunAs launches file with the "runas" verb (triggering the UAC
+// prompt) and returns a handle to the new process.
+func shellExecuteRunAs(file, parameters string) (windows.Handle, error) {
+	verbPtr, err := windows.UTF16PtrFromString("runas")
+	if err != nil {
+		return 0, err
+	}
+	filePtr, err := windows.UTF16PtrFromString(file)
+	if err != nil {
+		return 0, err
+	}
+	paramsPtr, err := windows.UTF16PtrFromString(parameters)
+	if err != nil {
+		return 0, err
+	}
+
+	info := &shellExecuteInfo{
+		fMask:        seeMaskNoCloseProcess,
+		lpVerb:       verbPtr,
+		lpFile:       filePtr,
+		lpParameters: paramsPtr,
+		nShow:        swShowNormal,
+	}
+	info.cbSize = uint32(unsafe.Sizeof(*info))
+
+	ret, _, err := procShellExecuteExW.Call(uintptr(unsafe.Pointer(info)))
+	if ret == 0 {
+		return 0, fmt.Errorf("ShellExecuteExW failed: %w", err)
+	}
+
+	return info.hProcess, nil
+}
+
+// MirrorWriter is returned to an elevated child so its Logger can tee
+// output to the named pipe back to the original, non-elevated console.
+type MirrorWriter struct {
+	conn io.WriteCloser
+}
+
+func (m *MirrorWriter) Write(p []byte) (int, error) {
+	return m.conn.Write(p)
+}
+
+// DialParentMirror connects to the named pipe created by EnsureElevated in
+// the non-elevated parent, if this process was launched with
+// envElevatePipe set. It returns nil, nil when not running as that child.
+func DialParentMirror() (*MirrorWriter, error) {
+	pipeName := os.Getenv(envElevatePipe)
+	if pipeName == "" {
+		return nil, nil
+	}
+
+	conn, err := dialNamedPipe(pipeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to parent console pipe: %w", err)
+	}
+
+	return &MirrorWriter{conn: conn}, nil
+}
+
+// dialNamedPipe opens an existing named pipe as a client for writing.
+func dialNamedPipe(name string) (io.WriteCloser, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := windows.CreateFile(
+		namePtr,
+		windows.GENERIC_WRITE,
+		0,
+		nil,
+		windows.OPEN_EXISTING,
+		0,
+		0,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(handle), name), nil
+}
+
+// pipeServer is the non-elevated parent's half of the console mirror pipe.
+type pipeServer struct {
+	handle windows.Handle
+}
+
+// kernel32.dll!CreateNamedPipeW / ConnectNamedPipe are not wrapped by
+// golang.org/x/sys/windows.
+var (
+	kernel32PipeDLL         = windows.NewLazySystemDLL("kernel32.dll")
+	procCreateNamedPipeW    = kernel32PipeDLL.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe    = kernel32PipeDLL.NewProc("ConnectNamedPipe")
+	procDisconnectNamedPipe = kernel32PipeDLL.NewProc("DisconnectNamedPipe")
+)
+
+const (
+	pipeAccessInbound      = 0x00000001
+	pipeTypeByte           = 0x00000000
+	pipeReadmodeByte       = 0x00000000
+	pipeWait               = 0x00000000
+	pipeUnlimitedInstances = 255
+)
+
+// newPipeServer creates and listens on a single-instance byte-mode named
+// pipe for the elevated child to connect to.
+func newPipeServer(name string) (*pipeServer, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		pipeAccessInbound,
+		pipeTypeByte|pipeReadmodeByte|pipeWait,
+		pipeUnlimitedInstances,
+		4096, // output buffer size, unused for inbound pipe
+		4096, // input buffer size
+		0,    // default timeout
+		0,    // default security attributes
+	)
+	if windows.Handle(ret) == windows.InvalidHandle {
+		return nil, fmt.Errorf("CreateNamedPipeW failed: %w", err)
+	}
+
+	return &pipeServer{handle: windows.Handle(ret)}, nil
+}
+
+// mirrorTo blocks waiting for the elevated child to connect, then copies
+// everything it writes to dst until the pipe closes.
+func (p *pipeServer) mirrorTo(dst io.Writer) {
+	ret, _, callErr := procConnectNamedPipe.Call(uintptr(p.handle), 0)
+	if ret == 0 && callErr != windows.ERROR_PIPE_CONNECTED {
+		return
+	}
+
+	f := os.NewFile(uintptr(p.handle), "elevated-console-mirror")
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fmt.Fprintln(dst, scanner.Text())
+	}
+}
+
+func (p *pipeServer) close() {
+	procDisconnectNamedPipe.Call(uintptr(p.handle))
+	windows.CloseHandle(p.handle)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -14,6 +16,12 @@ import (
 	"time"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/rwgb/eset.epop.tools/scripts/windows/msilog"
+	"github.com/rwgb/eset.epop.tools/scripts/windows/preflight"
+	"github.com/rwgb/eset.epop.tools/scripts/windows/uninstall"
+	"github.com/rwgb/eset.epop.tools/scripts/windows/updater"
+	"github.com/rwgb/eset.epop.tools/scripts/windows/verify"
 )
 
 const (
@@ -36,12 +44,15 @@ const (
 
 // Logger wraps log functionality with timestamps and colors
 type Logger struct {
-	file   *os.File
-	logger *log.Logger
+	file       *os.File
+	logger     *log.Logger
+	jsonOutput bool
 }
 
-// NewLogger creates a new logger instance
-func NewLogger(logPath string) (*Logger, error) {
+// NewLogger creates a new logger instance. When jsonOutput is true,
+// structured events logged via Event are also written to stdout as JSON
+// lines, for RMM platforms driving the tool programmatically.
+func NewLogger(logPath string, jsonOutput bool) (*Logger, error) {
 	// Create log directory if it doesn't exist
 	logDir := filepath.Dir(logPath)
 	if err := os.MkdirAll(logDir, 0755); err != nil {
@@ -58,10 +69,26 @@ func NewLogger(logPath string) (*Logger, error) {
 	mw := io.MultiWriter(os.Stdout, file)
 	logger := log.New(mw, "", 0)
 
-	return &Logger{
-		file:   file,
-		logger: logger,
-	}, nil
+	l := &Logger{
+		file:       file,
+		logger:     logger,
+		jsonOutput: jsonOutput,
+	}
+
+	// If this process is the elevated child relaunched by EnsureElevated,
+	// mirror everything back to the original console too.
+	if mirror, err := DialParentMirror(); err == nil && mirror != nil {
+		l.AddMirror(mirror)
+	}
+
+	return l, nil
+}
+
+// AddMirror tees subsequent log output to an additional writer, such as the
+// named pipe back to a non-elevated parent console.
+func (l *Logger) AddMirror(w io.Writer) {
+	mw := io.MultiWriter(os.Stdout, l.file, w)
+	l.logger = log.New(mw, "", 0)
 }
 
 // Close closes the log file
@@ -100,6 +127,32 @@ func (l *Logger) Step(format string, v ...interface{}) {
 	l.logger.Println("========================================")
 }
 
+// Event records a structured, machine-readable event (kind plus arbitrary
+// fields) as a JSON line, in addition to the colored human-readable output
+// produced by Info/Warn/Error/Step. It always goes to the log file; it also
+// goes to stdout when the logger was created with jsonOutput, so RMM
+// platforms can drive the tool off stdout alone.
+func (l *Logger) Event(kind string, fields map[string]interface{}) {
+	payload := map[string]interface{}{
+		"time": time.Now().Format(time.RFC3339),
+		"kind": kind,
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+
+	line, err := json.Marshal(payload)
+	if err != nil {
+		l.Warn("failed to marshal event %q: %v", kind, err)
+		return
+	}
+
+	fmt.Fprintln(l.file, string(line))
+	if l.jsonOutput {
+		fmt.Fprintln(os.Stdout, string(line))
+	}
+}
+
 // InstallConfig holds installation configuration
 type InstallConfig struct {
 	ConsolePassword string
@@ -107,6 +160,23 @@ type InstallConfig struct {
 	InstallPath     string
 	LogPath         string
 	SkipDownload    bool
+	ExpectedSHA256  string
+	InstallerURL    string
+	AutoFirewall    bool
+}
+
+// requiredForUnattended lists the InstallConfig fields that must be
+// populated (by -config, CLI flags, or the ESET_*_PASSWORD env vars) before
+// an -unattended run is allowed to proceed without prompting.
+func (c *InstallConfig) requiredForUnattended() []string {
+	var missing []string
+	if c.ConsolePassword == "" {
+		missing = append(missing, "ConsolePassword")
+	}
+	if c.DBPassword == "" {
+		missing = append(missing, "DBPassword")
+	}
+	return missing
 }
 
 // PromptForInput prompts user for input
@@ -165,12 +235,6 @@ func readPassword() (string, error) {
 	return strings.TrimSpace(input), nil
 }
 
-// CheckAdminPrivileges checks if running with administrator privileges
-func CheckAdminPrivileges() bool {
-	_, err := os.Open("\\\\.\\PHYSICALDRIVE0")
-	return err == nil
-}
-
 // DownloadFile downloads a file from URL with progress reporting
 func DownloadFile(logger *Logger, url, filepath string) error {
 	logger.Info("Downloading from: %s", url)
@@ -313,16 +377,56 @@ func RunMSIInstaller(logger *Logger, msiPath string, config *InstallConfig) erro
 		}
 	}()
 
+	// Tail the verbose MSI log for the structured events msiexec's own
+	// stdout/stderr almost never carries.
+	tailStop := make(chan struct{})
+	var lastAction string
+	var lastActionReturn int
+	tailDone := make(chan struct{})
+	go func() {
+		defer close(tailDone)
+		tailer := msilog.NewTailer(config.LogPath)
+		tailer.Follow(tailStop, func(evt msilog.Event) {
+			switch evt.Kind {
+			case msilog.KindActionStart:
+				logger.Event("action_start", map[string]interface{}{"action": evt.Action})
+			case msilog.KindActionEnd:
+				logger.Event("action_end", map[string]interface{}{
+					"action":      evt.Action,
+					"returnValue": evt.ReturnValue,
+				})
+				if evt.ReturnValue != 0 {
+					lastAction = evt.Action
+					lastActionReturn = evt.ReturnValue
+				}
+			case msilog.KindReturnCode:
+				logger.Event("return_code", map[string]interface{}{
+					"returnValue": evt.ReturnValue,
+					"description": msilog.ReturnCodeDescription(evt.ReturnValue),
+				})
+			}
+		})
+	}()
+
 	// Wait for installation to complete
 	logger.Info("Installation in progress... This may take several minutes.")
 	logger.Info("Detailed logs are being written to: %s", config.LogPath)
 
-	if err := cmd.Wait(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			logger.Error("Installation failed with exit code: %d", exitErr.ExitCode())
-			return fmt.Errorf("installation failed with exit code %d", exitErr.ExitCode())
+	waitErr := cmd.Wait()
+
+	close(tailStop)
+	<-tailDone
+
+	if waitErr != nil {
+		if exitErr, ok := waitErr.(*exec.ExitError); ok {
+			code := exitErr.ExitCode()
+			logger.Error("Installation failed with exit code: %d (%s)", code, msilog.ReturnCodeDescription(code))
+			if lastAction != "" {
+				logger.Error("Last failing action: %s (return value %d)", lastAction, lastActionReturn)
+			}
+			return fmt.Errorf("installation failed with exit code %d", code)
 		}
-		return fmt.Errorf("installation failed: %w", err)
+		return fmt.Errorf("installation failed: %w", waitErr)
 	}
 
 	logger.Info("Installation completed successfully!")
@@ -406,108 +510,412 @@ func VerifyInstallation(logger *Logger) error {
 	return nil
 }
 
-func main() {
-	// Create timestamp for log file
+// promptForConfig runs the interactive question flow and returns the
+// resulting InstallConfig. It is the default when no -config file or
+// CLI flags are supplied.
+func promptForConfig(logger *Logger, timestamp string) (*InstallConfig, error) {
+	logger.Step("Configuration")
+
+	fmt.Println("\nPlease provide the following information:")
+	fmt.Println("(Press Enter to use default values where applicable)")
+	fmt.Println()
+
+	consolePassword, err := PromptForInput("ESET Console Administrator Password: ", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	consolePasswordConfirm, err := PromptForInput("Confirm Console Password: ", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if consolePassword != consolePasswordConfirm {
+		return nil, fmt.Errorf("passwords do not match")
+	}
+
+	dbPassword, err := PromptForInput("Database Password: ", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	dbPasswordConfirm, err := PromptForInput("Confirm Database Password: ", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if dbPassword != dbPasswordConfirm {
+		return nil, fmt.Errorf("passwords do not match")
+	}
+
+	installPath, err := PromptForInput("Installation Path (press Enter for default): ", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	return &InstallConfig{
+		ConsolePassword: consolePassword,
+		DBPassword:      dbPassword,
+		InstallPath:     installPath,
+		LogPath:         filepath.Join(LogDirectory, fmt.Sprintf("eset-msi-%s.log", timestamp)),
+	}, nil
+}
+
+// flagConfig holds the values of the -config/-unattended CLI surface so
+// they can be parsed once in main and merged into an InstallConfig.
+type flagConfig struct {
+	configPath      string
+	unattended      bool
+	consolePassword string
+	dbPassword      string
+	installPath     string
+	logPath         string
+	installerURL    string
+	expectedSHA256  string
+	skipDownload    bool
+	autoFirewall    bool
+	sqlHost         string
+	domainJoin      bool
+	fix             bool
+}
+
+// resolveConfig builds the InstallConfig for this run. When -config or any
+// of the equivalent CLI flags are supplied, prompting is skipped for the
+// fields they cover; -unattended additionally requires ConsolePassword and
+// DBPassword to already be resolvable (flag, config file, or
+// ESET_*_PASSWORD env var) and fails fast instead of prompting.
+func resolveConfig(logger *Logger, timestamp string, fc flagConfig) (*InstallConfig, error) {
+	var config *InstallConfig
+
+	if fc.configPath != "" {
+		loaded, err := LoadConfigFile(fc.configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load -config: %w", err)
+		}
+		config = loaded
+	} else {
+		config = &InstallConfig{}
+	}
+
+	if fc.consolePassword != "" {
+		config.ConsolePassword = fc.consolePassword
+	} else if config.ConsolePassword == "" {
+		config.ConsolePassword = os.Getenv("ESET_CONSOLE_PASSWORD")
+	}
+
+	if fc.dbPassword != "" {
+		config.DBPassword = fc.dbPassword
+	} else if config.DBPassword == "" {
+		config.DBPassword = os.Getenv("ESET_DB_PASSWORD")
+	}
+
+	if fc.installPath != "" {
+		config.InstallPath = fc.installPath
+	}
+	if fc.installerURL != "" {
+		config.InstallerURL = fc.installerURL
+	}
+	if fc.expectedSHA256 != "" {
+		config.ExpectedSHA256 = fc.expectedSHA256
+	}
+	if fc.skipDownload {
+		config.SkipDownload = true
+	}
+	if fc.autoFirewall {
+		config.AutoFirewall = true
+	}
+	if fc.logPath != "" {
+		config.LogPath = fc.logPath
+	} else if config.LogPath == "" {
+		config.LogPath = filepath.Join(LogDirectory, fmt.Sprintf("eset-msi-%s.log", timestamp))
+	}
+
+	givenNonInteractively := fc.configPath != "" || fc.consolePassword != "" || fc.dbPassword != "" ||
+		fc.installPath != "" || fc.installerURL != "" || fc.expectedSHA256 != "" || fc.skipDownload || fc.autoFirewall
+
+	if fc.unattended {
+		if missing := config.requiredForUnattended(); len(missing) > 0 {
+			return nil, fmt.Errorf("-unattended requires %s (via flag, -config, or environment variable)", strings.Join(missing, ", "))
+		}
+		return config, nil
+	}
+
+	if givenNonInteractively {
+		if missing := config.requiredForUnattended(); len(missing) > 0 {
+			logger.Warn("Missing %s, falling back to interactive prompt for it", strings.Join(missing, ", "))
+			prompted, err := promptForConfig(logger, timestamp)
+			if err != nil {
+				return nil, err
+			}
+			if config.ConsolePassword == "" {
+				config.ConsolePassword = prompted.ConsolePassword
+			}
+			if config.DBPassword == "" {
+				config.DBPassword = prompted.DBPassword
+			}
+			if config.InstallPath == "" {
+				config.InstallPath = prompted.InstallPath
+			}
+		}
+		return config, nil
+	}
+
+	return promptForConfig(logger, timestamp)
+}
+
+// ManifestURL is the pinned location of the self-update manifest.
+const ManifestURL = "https://download.eset.com/com/eset/apps/business/era/updater/manifest.json"
+
+// runUpdate handles `eset.epop.tools update [--check-only] [--manifest-url=...]`.
+func runUpdate(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	checkOnly := fs.Bool("check-only", false, "only report whether a newer version is available")
+	manifestURL := fs.String("manifest-url", ManifestURL, "override the update manifest URL")
+	fs.Parse(args)
+
 	timestamp := time.Now().Format("20060102-150405")
-	logPath := filepath.Join(LogDirectory, fmt.Sprintf("eset-install-%s.log", timestamp))
+	logPath := filepath.Join(LogDirectory, fmt.Sprintf("eset-update-%s.log", timestamp))
 
-	// Create logger
-	logger, err := NewLogger(logPath)
+	logger, err := NewLogger(logPath, false)
 	if err != nil {
 		fmt.Printf("Failed to create logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Close()
 
-	// Print banner
-	logger.Info("========================================")
-	logger.Info("ESET Protect On-Prem Installer")
-	logger.Info("Windows All-in-One Installation")
-	logger.Info("========================================")
-	logger.Info("Log file: %s", logPath)
-	logger.Info("")
+	// -check-only only reads the manifest and the installed version; the
+	// actual stop/msiexec/start dance below needs admin rights.
+	if !*checkOnly {
+		if err := EnsureElevated(logger); err != nil {
+			logger.Error("Failed to elevate: %v", err)
+			os.Exit(1)
+		}
+	}
 
-	// Check prerequisites
-	if err := CheckPrerequisites(logger); err != nil {
-		logger.Error("Prerequisites check failed: %v", err)
-		logger.Error("Installation aborted")
+	if err := updater.Run(logger, *manifestURL, *checkOnly); err != nil {
+		logger.Error("Update failed: %v", err)
 		os.Exit(1)
 	}
+}
 
-	// Prompt for configuration
-	logger.Step("Configuration")
+// runUninstall handles `eset.epop.tools uninstall`.
+func runUninstall(args []string) {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "remove leftover C:\\ProgramData\\ESET artifacts without prompting")
+	fs.Parse(args)
 
-	fmt.Println("\nPlease provide the following information:")
-	fmt.Println("(Press Enter to use default values where applicable)")
-	fmt.Println()
+	timestamp := time.Now().Format("20060102-150405")
+	logPath := filepath.Join(LogDirectory, fmt.Sprintf("eset-uninstall-%s.log", timestamp))
 
-	consolePassword, err := PromptForInput("ESET Console Administrator Password: ", true)
+	logger, err := NewLogger(logPath, false)
 	if err != nil {
-		logger.Error("Failed to read password: %v", err)
+		fmt.Printf("Failed to create logger: %v\n", err)
 		os.Exit(1)
 	}
+	defer logger.Close()
 
-	consolePasswordConfirm, err := PromptForInput("Confirm Console Password: ", true)
-	if err != nil {
-		logger.Error("Failed to read password: %v", err)
+	if err := EnsureElevated(logger); err != nil {
+		logger.Error("Failed to elevate: %v", err)
 		os.Exit(1)
 	}
 
-	if consolePassword != consolePasswordConfirm {
-		logger.Error("Passwords do not match")
+	confirm := func() bool {
+		if *yes {
+			return true
+		}
+		response, err := PromptForInput("Remove leftover C:\\ProgramData\\ESET artifacts? (y/N): ", false)
+		return err == nil && strings.EqualFold(response, "y")
+	}
+
+	uninstallErr := uninstall.UninstallAll(logger, uninstall.Options{Confirm: confirm, LogDir: LogDirectory})
+	if uninstallErr != nil {
+		logger.Error("Uninstall failed: %v", uninstallErr)
+	}
+
+	if bundlePath, err := uninstall.CreateSupportBundle(logPath, "", []string{"ERA_Server", "ERA_Database", "EraAgentSvc", "MDMCore"}); err == nil {
+		logger.Info("Support bundle written to: %s", bundlePath)
+	}
+
+	if uninstallErr != nil {
 		os.Exit(1)
 	}
+}
 
-	dbPassword, err := PromptForInput("Database Password: ", true)
+// runRollback handles `eset.epop.tools rollback`, for manually restoring a
+// known-bad partial install outside of an in-progress RunMSIInstaller call.
+func runRollback(args []string) {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	fs.Parse(args)
+
+	timestamp := time.Now().Format("20060102-150405")
+	logPath := filepath.Join(LogDirectory, fmt.Sprintf("eset-rollback-%s.log", timestamp))
+
+	logger, err := NewLogger(logPath, false)
 	if err != nil {
-		logger.Error("Failed to read password: %v", err)
+		fmt.Printf("Failed to create logger: %v\n", err)
 		os.Exit(1)
 	}
+	defer logger.Close()
 
-	dbPasswordConfirm, err := PromptForInput("Confirm Database Password: ", true)
-	if err != nil {
-		logger.Error("Failed to read password: %v", err)
+	if err := EnsureElevated(logger); err != nil {
+		logger.Error("Failed to elevate: %v", err)
 		os.Exit(1)
 	}
 
-	if dbPassword != dbPasswordConfirm {
-		logger.Error("Passwords do not match")
+	rollbackErr := uninstall.Rollback(logger, LogDirectory)
+	if rollbackErr != nil {
+		logger.Error("Rollback failed: %v", rollbackErr)
+	}
+
+	if bundlePath, err := uninstall.CreateSupportBundle(logPath, "", []string{"ERA_Server", "ERA_Database", "EraAgentSvc", "MDMCore"}); err == nil {
+		logger.Info("Support bundle written to: %s", bundlePath)
+	}
+
+	if rollbackErr != nil {
 		os.Exit(1)
 	}
+}
 
-	installPath, err := PromptForInput("Installation Path (press Enter for default): ", false)
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "update":
+			runUpdate(os.Args[2:])
+			return
+		case "uninstall":
+			runUninstall(os.Args[2:])
+			return
+		case "rollback":
+			runRollback(os.Args[2:])
+			return
+		}
+	}
+
+	var fc flagConfig
+	flag.StringVar(&fc.configPath, "config", "", "path to a YAML or JSON InstallConfig file")
+	flag.BoolVar(&fc.unattended, "unattended", false, "skip all interactive prompts; fail fast if required fields are missing")
+	flag.StringVar(&fc.consolePassword, "console-password", "", "ESET Console Administrator password (overrides -config and ESET_CONSOLE_PASSWORD)")
+	flag.StringVar(&fc.dbPassword, "db-password", "", "database password (overrides -config and ESET_DB_PASSWORD)")
+	flag.StringVar(&fc.installPath, "install-path", "", "installation directory")
+	flag.StringVar(&fc.logPath, "log-path", "", "path for the MSI verbose log")
+	flag.StringVar(&fc.installerURL, "installer-url", "", "override the installer download URL")
+	expectedSHA256 := flag.String("expected-sha256", "", "expected SHA-256 hash of the installer MSI, for air-gapped hash pinning")
+	flag.BoolVar(&fc.skipDownload, "skip-download", false, "reuse an already-downloaded installer instead of fetching it again")
+	flag.BoolVar(&fc.autoFirewall, "auto-firewall", false, "automatically add Windows Firewall rules for ports 2222/2223")
+	output := flag.String("output", "text", "output mode: \"text\" (default) or \"json\" for RMM-driven runs")
+	flag.StringVar(&fc.sqlHost, "sql-host", "", "external MS SQL host to test connectivity to before installing")
+	flag.BoolVar(&fc.domainJoin, "domain-join", false, "require the machine to be domain-joined as a preflight check")
+	flag.BoolVar(&fc.fix, "fix", false, "attempt to auto-remediate fixable preflight failures (e.g. Defender exclusions)")
+	flag.Parse()
+	fc.expectedSHA256 = *expectedSHA256
+	jsonOutput := strings.EqualFold(*output, "json")
+
+	// Create timestamp for log file
+	timestamp := time.Now().Format("20060102-150405")
+	logPath := filepath.Join(LogDirectory, fmt.Sprintf("eset-install-%s.log", timestamp))
+
+	// Create logger
+	logger, err := NewLogger(logPath, jsonOutput)
 	if err != nil {
-		logger.Error("Failed to read input: %v", err)
+		fmt.Printf("Failed to create logger: %v\n", err)
 		os.Exit(1)
 	}
+	defer logger.Close()
 
-	config := &InstallConfig{
-		ConsolePassword: consolePassword,
-		DBPassword:      dbPassword,
-		InstallPath:     installPath,
-		LogPath:         filepath.Join(LogDirectory, fmt.Sprintf("eset-msi-%s.log", timestamp)),
+	// Print banner
+	logger.Info("========================================")
+	logger.Info("ESET Protect On-Prem Installer")
+	logger.Info("Windows All-in-One Installation")
+	logger.Info("========================================")
+	logger.Info("Log file: %s", logPath)
+	logger.Info("")
+
+	// Elevate automatically instead of telling the user to right-click ->
+	// Run as Administrator.
+	if err := EnsureElevated(logger); err != nil {
+		logger.Error("Failed to elevate: %v", err)
+		os.Exit(1)
+	}
+
+	// Check prerequisites
+	if err := CheckPrerequisites(logger); err != nil {
+		logger.Error("Prerequisites check failed: %v", err)
+		logger.Error("Installation aborted")
+		os.Exit(1)
+	}
+
+	// Gather configuration: interactively, unless -config/-unattended/
+	// equivalent flags were supplied.
+	config, err := resolveConfig(logger, timestamp, fc)
+	if err != nil {
+		logger.Error("Failed to resolve configuration: %v", err)
+		os.Exit(1)
 	}
 
 	logger.Info("Configuration collected successfully")
 
+	// Extended preflight checks: ports, SQL connectivity, domain join,
+	// Windows features, and Defender exclusions.
+	logger.Step("Running Preflight Checks")
+
+	preflightResults := preflight.Run(preflight.Options{
+		SQLHost:           fc.sqlHost,
+		RequireDomainJoin: fc.domainJoin,
+		InstallDir:        config.InstallPath,
+		Fix:               fc.fix,
+	})
+
+	var preflightFailed bool
+	for _, r := range preflightResults {
+		switch r.Severity {
+		case preflight.SeverityOK:
+			logger.Info("[%s] %s", r.Name, r.Message)
+		case preflight.SeverityWarning:
+			logger.Warn("[%s] %s", r.Name, r.Message)
+			if r.Remediation != "" {
+				logger.Warn("  remediation: %s", r.Remediation)
+			}
+		case preflight.SeverityError:
+			preflightFailed = true
+			logger.Error("[%s] %s", r.Name, r.Message)
+			if r.Remediation != "" {
+				logger.Error("  remediation: %s", r.Remediation)
+			}
+		}
+	}
+
+	if preflightFailed {
+		logger.Error("One or more preflight checks failed; re-run with -fix where applicable or address the remediation steps above")
+		os.Exit(1)
+	}
+
 	// Download installer
 	logger.Step("Downloading ESET Protect Installer")
 
 	installerPath := filepath.Join(os.TempDir(), InstallerFileName)
 
+	installerURL := ESETInstallerURL
+	if config.InstallerURL != "" {
+		installerURL = config.InstallerURL
+	}
+
 	// Check if installer already exists
 	if _, err := os.Stat(installerPath); err == nil {
 		logger.Info("Installer already exists at: %s", installerPath)
-		response, err := PromptForInput("Re-download installer? (y/N): ", false)
-		if err == nil && strings.ToLower(response) == "y" {
-			os.Remove(installerPath)
-		} else {
+		if fc.unattended {
 			config.SkipDownload = true
+		} else {
+			response, err := PromptForInput("Re-download installer? (y/N): ", false)
+			if err == nil && strings.ToLower(response) == "y" {
+				os.Remove(installerPath)
+			} else {
+				config.SkipDownload = true
+			}
 		}
 	}
 
 	if !config.SkipDownload {
-		if err := DownloadFile(logger, ESETInstallerURL, installerPath); err != nil {
+		if err := DownloadFile(logger, installerURL, installerPath); err != nil {
 			logger.Error("Failed to download installer: %v", err)
 			logger.Error("Installation aborted")
 			os.Exit(1)
@@ -523,12 +931,42 @@ func main() {
 	fileInfo, _ := os.Stat(installerPath)
 	logger.Info("Installer file size: %.2f MB", float64(fileInfo.Size())/(1024*1024))
 
+	// Verify installer integrity before it is ever handed to msiexec
+	logger.Step("Verifying Installer Integrity")
+
+	if config.ExpectedSHA256 != "" {
+		if err := verify.VerifySHA256(installerPath, config.ExpectedSHA256); err != nil {
+			logger.Error("SHA-256 verification failed: %v", err)
+			os.Remove(installerPath)
+			os.Exit(1)
+		}
+		logger.Info("SHA-256 hash matches expected value")
+	} else {
+		logger.Warn("No -expected-sha256 supplied; skipping hash pinning check")
+	}
+
+	if err := verify.VerifyAuthenticode(installerPath); err != nil {
+		logger.Error("Authenticode verification failed: %v", err)
+		os.Remove(installerPath)
+		os.Exit(1)
+	}
+	logger.Info("Authenticode signature verified")
+
 	// Run installation
 	logger.Step("Running Installation")
 
 	if err := RunMSIInstaller(logger, installerPath, config); err != nil {
 		logger.Error("Installation failed: %v", err)
 		logger.Error("Please check the detailed log at: %s", config.LogPath)
+
+		if rbErr := uninstall.Rollback(logger, filepath.Dir(config.LogPath)); rbErr != nil {
+			logger.Error("Rollback also failed: %v", rbErr)
+		}
+
+		if bundlePath, bErr := uninstall.CreateSupportBundle(logPath, config.LogPath, []string{"ERA_Server", "ERA_Database", "EraAgentSvc", "MDMCore"}); bErr == nil {
+			logger.Info("Support bundle written to: %s", bundlePath)
+		}
+
 		logger.Error("Installation aborted")
 		os.Exit(1)
 	}
@@ -556,10 +994,12 @@ func main() {
 	logger.Info("")
 
 	// Optional: Clean up installer
-	response, err := PromptForInput("Delete installer file? (y/N): ", false)
-	if err == nil && strings.ToLower(response) == "y" {
-		os.Remove(installerPath)
-		logger.Info("Installer file deleted")
+	if !fc.unattended {
+		response, err := PromptForInput("Delete installer file? (y/N): ", false)
+		if err == nil && strings.ToLower(response) == "y" {
+			os.Remove(installerPath)
+			logger.Info("Installer file deleted")
+		}
 	}
 
 	logger.Info("Thank you for installing ESET Protect On-Prem!")
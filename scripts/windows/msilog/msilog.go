@@ -0,0 +1,198 @@
+// Package msilog tails an msiexec /l*v verbose log while an installation
+// runs and turns the well-known lines it writes into structured events, so
+// callers can surface progress and failures without scraping msiexec's
+// largely empty stdout.
+package msilog
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Kind enumerates the event types ParseLine recognizes.
+type Kind string
+
+const (
+	KindActionStart Kind = "action_start"
+	KindActionEnd   Kind = "action_end"
+	KindProperty    Kind = "property"
+	KindReturnCode  Kind = "return_code"
+	KindRaw         Kind = "raw"
+)
+
+// Event is a single structured line extracted from the MSI log.
+type Event struct {
+	Time        time.Time `json:"time"`
+	Kind        Kind      `json:"kind"`
+	Action      string    `json:"action,omitempty"`
+	Property    string    `json:"property,omitempty"`
+	Value       string    `json:"value,omitempty"`
+	ReturnValue int       `json:"returnValue,omitempty"`
+	Raw         string    `json:"raw"`
+}
+
+// Well-known MSI return/error codes worth calling out by name.
+var KnownReturnCodes = map[int]string{
+	0:    "ERROR_SUCCESS",
+	1603: "ERROR_INSTALL_FAILURE",
+	1618: "ERROR_INSTALL_ALREADY_RUNNING",
+	1638: "ERROR_PRODUCT_VERSION (another version is already installed)",
+	3010: "ERROR_SUCCESS_REBOOT_REQUIRED",
+}
+
+var (
+	actionStartRe = regexp.MustCompile(`^Action start \d{1,2}:\d{2}:\d{2}: ([^.]+)\.$`)
+	actionEndRe   = regexp.MustCompile(`^Action ended \d{1,2}:\d{2}:\d{2}: ([^.]+)\. Return value (\d+)\.$`)
+	propertyRe    = regexp.MustCompile(`Property\(\w\): ([A-Za-z0-9_]+) = (.*)$`)
+	returnCodeRe  = regexp.MustCompile(`[Rr]eturn [Vv]alue (\d+)\.?$`)
+)
+
+// ParseLine extracts a structured Event from a single line of an msiexec
+// /l*v log, stripping the leading "MSI (s) (xx:xx) [hh:mm:ss:ttt]:" prefix
+// if present. Every line produces an event; uninteresting lines come back
+// as KindRaw so callers can still archive them.
+func ParseLine(line string) Event {
+	body := stripLogPrefix(line)
+	now := time.Now()
+
+	if m := actionStartRe.FindStringSubmatch(body); m != nil {
+		return Event{Time: now, Kind: KindActionStart, Action: m[1], Raw: line}
+	}
+
+	if m := actionEndRe.FindStringSubmatch(body); m != nil {
+		rv, _ := strconv.Atoi(m[2])
+		return Event{Time: now, Kind: KindActionEnd, Action: m[1], ReturnValue: rv, Raw: line}
+	}
+
+	if m := propertyRe.FindStringSubmatch(body); m != nil {
+		return Event{Time: now, Kind: KindProperty, Property: m[1], Value: m[2], Raw: line}
+	}
+
+	if m := returnCodeRe.FindStringSubmatch(body); m != nil {
+		rv, _ := strconv.Atoi(m[1])
+		return Event{Time: now, Kind: KindReturnCode, ReturnValue: rv, Raw: line}
+	}
+
+	return Event{Time: now, Kind: KindRaw, Raw: line}
+}
+
+// stripLogPrefix removes the "MSI (s) (E8:38) [10:15:23:456]: " style
+// prefix msiexec prepends to every verbose log line.
+func stripLogPrefix(line string) string {
+	idx := lastIndex(line, "]: ")
+	if idx == -1 {
+		return line
+	}
+	return line[idx+len("]: "):]
+}
+
+func lastIndex(s, sub string) int {
+	for i := len(s) - len(sub); i >= 0; i-- {
+		if s[i:i+len(sub)] == sub {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReturnCodeDescription returns a human label for a well-known MSI return
+// code, or a generic fallback for an unrecognized one.
+func ReturnCodeDescription(code int) string {
+	if desc, ok := KnownReturnCodes[code]; ok {
+		return desc
+	}
+	return fmt.Sprintf("unrecognized return code %d", code)
+}
+
+// Tailer follows an msiexec log file as it grows, polling for new lines
+// since the file may not exist yet when the install is first kicked off.
+type Tailer struct {
+	path string
+}
+
+// NewTailer returns a Tailer for the given msiexec /l*v log path.
+func NewTailer(path string) *Tailer {
+	return &Tailer{path: path}
+}
+
+// Follow polls path for new lines and calls onEvent for each one, until
+// stop is closed. It tolerates the file not existing yet, which is normal
+// immediately after msiexec is launched.
+func (t *Tailer) Follow(stop <-chan struct{}, onEvent func(Event)) error {
+	var (
+		file    *os.File
+		reader  *bufio.Reader
+		pending string // line fragment read mid-write, carried to the next tick
+	)
+
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			t.drain(reader, &pending, onEvent)
+			return nil
+		case <-ticker.C:
+			if file == nil {
+				f, err := os.Open(t.path)
+				if err != nil {
+					continue // log not created yet
+				}
+				file = f
+				reader = bufio.NewReader(file)
+			}
+
+			for {
+				line, err := reader.ReadString('\n')
+				pending += line
+				if err != nil {
+					break // partial line mid-write; wait and retry next tick
+				}
+				onEvent(ParseLine(trimNewline(pending)))
+				pending = ""
+			}
+		}
+	}
+}
+
+// drain reads whatever is left in reader after stop fires, so the final
+// lines msiexec wrote aren't lost to a race with the tick interval. Unlike
+// Follow's poll loop, a partial final line (msiexec exited without a
+// trailing newline) is still flushed, since there won't be another tick to
+// complete it.
+func (t *Tailer) drain(reader *bufio.Reader, pending *string, onEvent func(Event)) {
+	if reader != nil {
+		for {
+			line, err := reader.ReadString('\n')
+			*pending += line
+			if err != nil {
+				break
+			}
+			onEvent(ParseLine(trimNewline(*pending)))
+			*pending = ""
+		}
+	}
+
+	if *pending != "" {
+		onEvent(ParseLine(trimNewline(*pending)))
+		*pending = ""
+	}
+}
+
+func trimNewline(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package preflight
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// netapi32.dll!NetGetJoinInformation / NetApiBufferFree are not wrapped by
+// golang.org/x/sys/windows.
+var (
+	netapi32DLL               = windows.NewLazySystemDLL("netapi32.dll")
+	procNetGetJoinInformation = netapi32DLL.NewProc("NetGetJoinInformation")
+	procNetApiBufferFree      = netapi32DLL.NewProc("NetApiBufferFree")
+)
+
+// NETSETUP_JOIN_STATUS values.
+const (
+	netSetupUnknownStatus = 0
+	netSetupUnjoined      = 1
+	netSetupWorkgroupName = 2
+	netSetupDomainName    = 3
+)
+
+// netGetJoinInformation wraps NetGetJoinInformation for the local machine,
+// returning the domain/workgroup name and whether it's an actual domain
+// join (as opposed to a workgroup).
+func netGetJoinInformation() (name string, isDomainJoined bool, err error) {
+	var namePtr *uint16
+	var status uint32
+
+	ret, _, callErr := procNetGetJoinInformation.Call(
+		0, // lpServer = NULL, local machine
+		uintptr(unsafe.Pointer(&namePtr)),
+		uintptr(unsafe.Pointer(&status)),
+	)
+	if ret != 0 {
+		return "", false, fmt.Errorf("NetGetJoinInformation failed: %w", callErr)
+	}
+	defer procNetApiBufferFree.Call(uintptr(unsafe.Pointer(namePtr)))
+
+	name = windows.UTF16PtrToString(namePtr)
+	return name, status == netSetupDomainName, nil
+}
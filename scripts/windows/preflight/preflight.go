@@ -0,0 +1,269 @@
+// Package preflight runs the connectivity, domain, feature, and Defender
+// exclusion checks expected before an ERA_Server install, beyond the basic
+// OS version / disk space / admin rights checks in the main package.
+package preflight
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Severity classifies how serious a PreflightResult is.
+type Severity string
+
+const (
+	SeverityOK      Severity = "ok"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Result is the outcome of a single preflight probe.
+type Result struct {
+	Name        string
+	Severity    Severity
+	Message     string
+	Remediation string
+}
+
+// requiredFreePorts must be unbound before install so the ERA services can
+// claim them: console (2222/2223), mobile device connector (2195/2196 push
+// relay), and the web console (443).
+var requiredFreePorts = []int{2222, 2223, 2195, 2196, 443}
+
+// Options configures which probes Run performs.
+type Options struct {
+	// SQLHost is an external MS SQL host to test connectivity to. Left
+	// empty when using the bundled SQL install, in which case the TCP
+	// 1433 check is deferred until after install.
+	SQLHost string
+	// RequireDomainJoin, when true, fails the domain-join probe if the
+	// machine is not joined to a domain.
+	RequireDomainJoin bool
+	// InstallDir is checked for a Windows Defender real-time protection
+	// exclusion.
+	InstallDir string
+	// Fix attempts to auto-remediate probes that support it (currently
+	// just the Defender exclusion, via Add-MpPreference).
+	Fix bool
+}
+
+// Run executes every preflight probe and returns one Result per probe.
+func Run(opts Options) []Result {
+	results := []Result{
+		checkPortsFree(requiredFreePorts),
+		checkSQLConnectivity(opts.SQLHost),
+		checkDomainJoin(opts.RequireDomainJoin),
+		checkDotNetVersion(),
+		checkIIS(),
+		checkDefenderExclusion(opts.InstallDir, opts.Fix),
+	}
+	return results
+}
+
+// checkPortsFree attempts to bind each port on 0.0.0.0 to confirm nothing
+// else is already listening on it.
+func checkPortsFree(ports []int) Result {
+	var busy []int
+	for _, port := range ports {
+		addr := fmt.Sprintf("0.0.0.0:%d", port)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			busy = append(busy, port)
+			continue
+		}
+		l.Close()
+	}
+
+	if len(busy) == 0 {
+		return Result{Name: "Required ports free", Severity: SeverityOK,
+			Message: fmt.Sprintf("ports %v are free", ports)}
+	}
+
+	return Result{
+		Name:        "Required ports free",
+		Severity:    SeverityError,
+		Message:     fmt.Sprintf("ports already in use: %v", busy),
+		Remediation: "stop whatever is listening on these ports, or move ERA_Server to a different host",
+	}
+}
+
+// checkSQLConnectivity dials the external SQL host's TCP 1433 if one is
+// configured; otherwise it's deferred to after install, when the bundled
+// SQL instance exists to test against.
+func checkSQLConnectivity(host string) Result {
+	if host == "" {
+		return Result{
+			Name:     "SQL connectivity",
+			Severity: SeverityOK,
+			Message:  "no external SQL host configured; bundled SQL install will be checked on TCP 1433 after install",
+		}
+	}
+
+	addr := fmt.Sprintf("%s:1433", host)
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return Result{
+			Name:        "SQL connectivity",
+			Severity:    SeverityError,
+			Message:     fmt.Sprintf("failed to reach %s: %v", addr, err),
+			Remediation: "verify the SQL host is reachable and TCP 1433 is open through any firewalls between here and there",
+		}
+	}
+	conn.Close()
+
+	return Result{Name: "SQL connectivity", Severity: SeverityOK, Message: fmt.Sprintf("%s is reachable", addr)}
+}
+
+// checkDomainJoin validates domain membership via NetGetJoinInformation
+// when required is true; it is informational otherwise.
+func checkDomainJoin(required bool) Result {
+	domain, joined, err := netGetJoinInformation()
+	if err != nil {
+		return Result{
+			Name:        "Domain join",
+			Severity:    SeverityWarning,
+			Message:     fmt.Sprintf("failed to query join status: %v", err),
+			Remediation: "re-run as Administrator; NetGetJoinInformation requires elevated access",
+		}
+	}
+
+	if !required {
+		if joined {
+			return Result{Name: "Domain join", Severity: SeverityOK, Message: fmt.Sprintf("joined to domain %s", domain)}
+		}
+		return Result{Name: "Domain join", Severity: SeverityOK, Message: "not domain-joined (not required)"}
+	}
+
+	if !joined {
+		return Result{
+			Name:        "Domain join",
+			Severity:    SeverityError,
+			Message:     "machine is not domain-joined",
+			Remediation: "join the machine to the required domain, or drop -domain-join if that's not actually needed",
+		}
+	}
+
+	return Result{Name: "Domain join", Severity: SeverityOK, Message: fmt.Sprintf("joined to domain %s", domain)}
+}
+
+// minDotNet472Release is the registry Release DWORD value that corresponds
+// to .NET Framework 4.7.2 (see Microsoft's NDP detection table).
+const minDotNet472Release = 461808
+
+// checkDotNetVersion reads the installed .NET Framework 4.x release number
+// from the registry and requires at least 4.7.2.
+func checkDotNetVersion() Result {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\NET Framework Setup\NDP\v4\Full`, registry.QUERY_VALUE)
+	if err != nil {
+		return Result{
+			Name:        ".NET Framework 4.7.2+",
+			Severity:    SeverityError,
+			Message:     ".NET Framework 4.x not found",
+			Remediation: "install .NET Framework 4.7.2 or later",
+		}
+	}
+	defer key.Close()
+
+	release, _, err := key.GetIntegerValue("Release")
+	if err != nil {
+		return Result{
+			Name:        ".NET Framework 4.7.2+",
+			Severity:    SeverityWarning,
+			Message:     "could not read Release value",
+			Remediation: "verify .NET Framework 4.7.2 or later is installed",
+		}
+	}
+
+	if release < minDotNet472Release {
+		return Result{
+			Name:        ".NET Framework 4.7.2+",
+			Severity:    SeverityError,
+			Message:     fmt.Sprintf("installed release %d is older than 4.7.2 (%d)", release, minDotNet472Release),
+			Remediation: "install .NET Framework 4.7.2 or later",
+		}
+	}
+
+	return Result{Name: ".NET Framework 4.7.2+", Severity: SeverityOK, Message: fmt.Sprintf("release %d installed", release)}
+}
+
+// checkIIS confirms IIS is installed, via the InetStp registry key it
+// publishes.
+func checkIIS() Result {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\InetStp`, registry.QUERY_VALUE)
+	if err != nil {
+		return Result{
+			Name:        "IIS",
+			Severity:    SeverityWarning,
+			Message:     "IIS does not appear to be installed",
+			Remediation: "install the Web Server (IIS) role if this server will host the Mobile Device Connector's web services",
+		}
+	}
+	defer key.Close()
+
+	version, _, err := key.GetStringValue("VersionString")
+	if err != nil {
+		return Result{Name: "IIS", Severity: SeverityOK, Message: "IIS installed"}
+	}
+
+	return Result{Name: "IIS", Severity: SeverityOK, Message: fmt.Sprintf("IIS %s installed", version)}
+}
+
+// checkDefenderExclusion checks for a Windows Defender real-time protection
+// exclusion covering installDir, and adds one via Add-MpPreference when fix
+// is true.
+func checkDefenderExclusion(installDir string, fix bool) Result {
+	if installDir == "" {
+		return Result{Name: "Defender exclusion", Severity: SeverityOK, Message: "no install directory specified yet; skipping"}
+	}
+
+	if hasDefenderExclusion(installDir) {
+		return Result{Name: "Defender exclusion", Severity: SeverityOK, Message: fmt.Sprintf("%s is already excluded", installDir)}
+	}
+
+	if fix {
+		if err := addDefenderExclusion(installDir); err != nil {
+			return Result{
+				Name:        "Defender exclusion",
+				Severity:    SeverityWarning,
+				Message:     fmt.Sprintf("failed to add exclusion automatically: %v", err),
+				Remediation: fmt.Sprintf("run: Add-MpPreference -ExclusionPath \"%s\"", installDir),
+			}
+		}
+		return Result{Name: "Defender exclusion", Severity: SeverityOK, Message: fmt.Sprintf("added exclusion for %s", installDir)}
+	}
+
+	return Result{
+		Name:        "Defender exclusion",
+		Severity:    SeverityWarning,
+		Message:     fmt.Sprintf("%s has no Windows Defender real-time protection exclusion", installDir),
+		Remediation: fmt.Sprintf("run with -fix, or manually: Add-MpPreference -ExclusionPath \"%s\"", installDir),
+	}
+}
+
+// hasDefenderExclusion checks the Defender exclusion paths registry key for
+// an entry matching dir.
+func hasDefenderExclusion(dir string) bool {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows Defender\Exclusions\Paths`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+
+	_, _, err = key.GetIntegerValue(dir)
+	return err == nil
+}
+
+// addDefenderExclusion shells out to PowerShell's Add-MpPreference, since
+// there is no first-class Win32 API for Defender exclusions. dir is passed
+// as a bound script parameter rather than formatted into the command text,
+// since it comes from -install-path/-config and could otherwise break out
+// of the quoted argument.
+func addDefenderExclusion(dir string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+		"& { param($Path) Add-MpPreference -ExclusionPath $Path }", "-Path", dir)
+	return cmd.Run()
+}
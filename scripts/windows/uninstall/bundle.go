@@ -0,0 +1,100 @@
+package uninstall
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// eventLogQueries pulls MsiInstaller/ESET entries out of the two logs most
+// likely to have something relevant to an install/uninstall failure.
+var eventLogQueries = []struct {
+	channel string
+	xpath   string
+}{
+	{"Application", "*[System[Provider[@Name='MsiInstaller']]]"},
+	{"Application", "*[System[Provider[@Name='ESET']]]"},
+	{"System", "*[System[Provider[@Name='MsiInstaller']]]"},
+}
+
+// CreateSupportBundle zips up the installer log, the MSI log, relevant
+// Windows Event Log excerpts, and `sc query` output for every service in
+// serviceNames, so a user can attach the result to a support ticket.
+// installerLogPath or msiLogPath may be empty if not applicable.
+func CreateSupportBundle(installerLogPath, msiLogPath string, serviceNames []string) (string, error) {
+	bundlePath := filepath.Join(os.TempDir(), fmt.Sprintf("eset-support-%s.zip", time.Now().Format("20060102-150405")))
+
+	out, err := os.Create(bundlePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", bundlePath, err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	defer zw.Close()
+
+	if installerLogPath != "" {
+		addFileToZip(zw, installerLogPath, "installer.log")
+	}
+	if msiLogPath != "" {
+		addFileToZip(zw, msiLogPath, "msi.log")
+	}
+
+	addEventLogExcerpts(zw)
+	addServiceStatus(zw, serviceNames)
+
+	return bundlePath, nil
+}
+
+// addFileToZip copies src into the archive under name, silently skipping it
+// if it can't be read (e.g. the MSI log was never created).
+func addFileToZip(zw *zip.Writer, src, name string) {
+	f, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return
+	}
+
+	io.Copy(w, f)
+}
+
+// addEventLogExcerpts runs wevtutil qe for each query in eventLogQueries
+// and adds the output as a text file per query.
+func addEventLogExcerpts(zw *zip.Writer) {
+	for i, q := range eventLogQueries {
+		output, err := exec.Command("wevtutil", "qe", q.channel, "/q:"+q.xpath, "/f:text").CombinedOutput()
+		if err != nil && len(output) == 0 {
+			continue
+		}
+
+		name := fmt.Sprintf("eventlog-%s-%d.txt", q.channel, i)
+		w, err := zw.Create(name)
+		if err != nil {
+			continue
+		}
+		w.Write(output)
+	}
+}
+
+// addServiceStatus runs `sc query` for each service and adds the combined
+// output as a single text file.
+func addServiceStatus(zw *zip.Writer, serviceNames []string) {
+	w, err := zw.Create("sc-query.txt")
+	if err != nil {
+		return
+	}
+
+	for _, name := range serviceNames {
+		output, _ := exec.Command("sc", "query", name).CombinedOutput()
+		fmt.Fprintf(w, "=== %s ===\n%s\n", name, output)
+	}
+}
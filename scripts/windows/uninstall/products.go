@@ -0,0 +1,89 @@
+package uninstall
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// uninstallKey is the standard Windows per-machine Add/Remove Programs
+// registry location.
+const uninstallKey = `Software\Microsoft\Windows\CurrentVersion\Uninstall`
+
+// Product is an installed MSI product discovered under the Uninstall key.
+type Product struct {
+	ProductCode string
+	DisplayName string
+	Publisher   string
+}
+
+// esetUninstallOrder lists known ESET display-name substrings in the order
+// they should be removed: dependents before the components they depend on.
+var esetUninstallOrder = []string{
+	"ESET Remote Administrator Console",
+	"ESET Remote Administrator MDM",
+	"ESET Remote Administrator Server",
+	"ESET Remote Administrator Agent",
+}
+
+// EnumerateESETProducts walks HKLM\...\Uninstall and returns every entry
+// whose Publisher contains "ESET", ordered so dependents are uninstalled
+// before the components they depend on.
+func EnumerateESETProducts() ([]Product, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, uninstallKey, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", uninstallKey, err)
+	}
+	defer key.Close()
+
+	names, err := key.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate uninstall entries: %w", err)
+	}
+
+	var products []Product
+	for _, name := range names {
+		subKey, err := registry.OpenKey(registry.LOCAL_MACHINE, uninstallKey+`\`+name, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+
+		publisher, _, _ := subKey.GetStringValue("Publisher")
+		if !strings.Contains(publisher, "ESET") {
+			subKey.Close()
+			continue
+		}
+
+		displayName, _, _ := subKey.GetStringValue("DisplayName")
+		subKey.Close()
+
+		products = append(products, Product{
+			ProductCode: name,
+			DisplayName: displayName,
+			Publisher:   publisher,
+		})
+	}
+
+	orderProducts(products)
+	return products, nil
+}
+
+// orderProducts sorts products in-place according to esetUninstallOrder,
+// with anything unrecognized left at the end in discovery order.
+func orderProducts(products []Product) {
+	rank := func(displayName string) int {
+		for i, known := range esetUninstallOrder {
+			if strings.Contains(displayName, known) {
+				return i
+			}
+		}
+		return len(esetUninstallOrder)
+	}
+
+	for i := 1; i < len(products); i++ {
+		for j := i; j > 0 && rank(products[j].DisplayName) < rank(products[j-1].DisplayName); j-- {
+			products[j], products[j-1] = products[j-1], products[j]
+		}
+	}
+}
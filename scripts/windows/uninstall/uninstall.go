@@ -0,0 +1,104 @@
+// Package uninstall implements `eset.epop.tools uninstall` and
+// `eset.epop.tools rollback`: tearing down installed ESET products via the
+// SCM and msiexec /x, and bundling diagnostics for support tickets.
+package uninstall
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/rwgb/eset.epop.tools/scripts/windows/updater"
+)
+
+// Logger is the subset of the main package's *Logger used here, declared
+// locally so this package has no dependency on package main.
+type Logger interface {
+	Info(format string, v ...interface{})
+	Warn(format string, v ...interface{})
+	Error(format string, v ...interface{})
+	Step(format string, v ...interface{})
+}
+
+// managedServices are stopped before any product is removed.
+var managedServices = []string{"ERA_Server", "ERA_Database", "EraAgentSvc", "MDMCore"}
+
+// ProgramDataDir is where ESET leaves artifacts behind after msiexec /x.
+const ProgramDataDir = `C:\ProgramData\ESET`
+
+// Options configures an uninstall/rollback run.
+type Options struct {
+	// Confirm is called before removing ProgramDataDir; it should return
+	// true to proceed. A nil Confirm skips that cleanup entirely.
+	Confirm func() bool
+	// LogDir is where each product's msiexec /x log is written.
+	LogDir string
+}
+
+// UninstallAll stops the managed services, then runs msiexec /x for every
+// installed ESET product in dependency order, and optionally removes
+// leftover ProgramDataDir artifacts.
+func UninstallAll(logger Logger, opts Options) error {
+	products, err := EnumerateESETProducts()
+	if err != nil {
+		return fmt.Errorf("failed to enumerate installed ESET products: %w", err)
+	}
+
+	if len(products) == 0 {
+		logger.Info("No ESET products found under the Uninstall registry key")
+		return nil
+	}
+
+	logger.Info("Stopping managed services before uninstall")
+	if err := updater.StopServices(logger, managedServices); err != nil {
+		logger.Warn("Failed to stop services cleanly: %v", err)
+	}
+
+	for _, p := range products {
+		logger.Step("Uninstalling %s (%s)", p.DisplayName, p.ProductCode)
+		logPath := filepath.Join(opts.LogDir, fmt.Sprintf("uninstall-%s.log", p.ProductCode))
+		if err := runMsiexecUninstall(p.ProductCode, logPath); err != nil {
+			logger.Error("Failed to uninstall %s: %v", p.DisplayName, err)
+			continue
+		}
+		logger.Info("Uninstalled %s", p.DisplayName)
+	}
+
+	if opts.Confirm != nil && opts.Confirm() {
+		logger.Info("Removing leftover artifacts under %s", ProgramDataDir)
+		if err := os.RemoveAll(ProgramDataDir); err != nil {
+			logger.Warn("Failed to remove %s: %v", ProgramDataDir, err)
+		}
+	}
+
+	return nil
+}
+
+// Rollback is invoked automatically when RunMSIInstaller returns non-zero.
+// It uninstalls any ESET products that made it onto the registry's
+// Uninstall key before the failure, restoring the prior (pre-install)
+// state. It never prompts and never removes ProgramDataDir, since a failed
+// install's logs live there and are useful for diagnosing what went wrong.
+func Rollback(logger Logger, logDir string) error {
+	logger.Step("Rolling back partially-installed components")
+	return UninstallAll(logger, Options{LogDir: logDir})
+}
+
+// runMsiexecUninstall runs msiexec /x for productCode in quiet mode with
+// verbose logging.
+func runMsiexecUninstall(productCode, logPath string) error {
+	cmd := exec.Command("msiexec",
+		"/x", productCode,
+		"/qn",
+		"/l*v", logPath,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("msiexec /x exited with error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Manifest describes the latest available installer, as published at a
+// pinned JSON URL by the release process.
+type Manifest struct {
+	Version     string `json:"version"`
+	SHA256      string `json:"sha256"`
+	DownloadURL string `json:"downloadUrl"`
+}
+
+// serverRegistryKey is where the installed ERA_Server version is recorded.
+const serverRegistryKey = `Software\ESET\RemoteAdministrator\Server`
+
+// FetchManifest downloads and parses the update manifest at url.
+func FetchManifest(url string) (*Manifest, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("manifest request returned status %s", resp.Status)
+	}
+
+	var m Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if m.Version == "" || m.DownloadURL == "" {
+		return nil, fmt.Errorf("manifest is missing version or downloadUrl")
+	}
+
+	return &m, nil
+}
+
+// InstalledVersion reads the currently installed ERA_Server version from the
+// registry. It returns an empty string with no error if ERA_Server has never
+// been installed.
+func InstalledVersion() (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, serverRegistryKey, registry.QUERY_VALUE)
+	if err != nil {
+		if err == registry.ErrNotExist {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to open %s: %w", serverRegistryKey, err)
+	}
+	defer key.Close()
+
+	version, _, err := key.GetStringValue("Version")
+	if err != nil {
+		if err == registry.ErrNotExist {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read Version value: %w", err)
+	}
+
+	return version, nil
+}
+
+// IsNewer reports whether remote is a newer version than local, comparing
+// dot-separated numeric components (e.g. "11.1.4567.0" > "11.1.4500.0").
+// A local version of "" is always considered older.
+func IsNewer(remote, local string) bool {
+	if local == "" {
+		return true
+	}
+
+	remoteParts := strings.Split(remote, ".")
+	localParts := strings.Split(local, ".")
+
+	for i := 0; i < len(remoteParts) || i < len(localParts); i++ {
+		var r, l int
+		if i < len(remoteParts) {
+			r, _ = strconv.Atoi(remoteParts[i])
+		}
+		if i < len(localParts) {
+			l, _ = strconv.Atoi(localParts[i])
+		}
+		if r != l {
+			return r > l
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,372 @@
+// Package updater implements eset.epop.tools's self-update subsystem: check
+// a pinned manifest for a newer ERA_Server build, and if one exists, relaunch
+// from a temp copy of the current binary so the MSI is free to overwrite the
+// real one on disk.
+package updater
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/rwgb/eset.epop.tools/scripts/windows/verify"
+)
+
+// Logger is the subset of the main package's *Logger used here, declared
+// locally so this package has no dependency on package main.
+type Logger interface {
+	Info(format string, v ...interface{})
+	Warn(format string, v ...interface{})
+	Error(format string, v ...interface{})
+}
+
+// EnvUpdateMSI and EnvUpdateParentPID are the environment variables the
+// parent process sets when re-exec'ing the temp self-copy.
+const (
+	EnvUpdateMSI       = "EPOP_UPDATE_MSI"
+	EnvUpdateParentPID = "EPOP_UPDATE_PARENT_PID"
+)
+
+// managedServices are stopped before msiexec runs and restarted afterward.
+var managedServices = []string{"ERA_Server", "ERA_Database"}
+
+// CheckForUpdate fetches the manifest at manifestURL and reports whether it
+// describes a version newer than what's currently installed.
+func CheckForUpdate(manifestURL string) (*Manifest, bool, error) {
+	manifest, err := FetchManifest(manifestURL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	installed, err := InstalledVersion()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return manifest, IsNewer(manifest.Version, installed), nil
+}
+
+// Run is the entry point for `eset.epop.tools update`. If this process is
+// the re-exec'd temp copy (EnvUpdateMSI is set), it performs the actual
+// service stop / msiexec / service start dance. Otherwise it checks the
+// manifest and, if checkOnly is false and an update is available, downloads
+// the new MSI and relaunches itself from a temp copy to apply it.
+func Run(logger Logger, manifestURL string, checkOnly bool) error {
+	if os.Getenv(EnvUpdateMSI) != "" {
+		return runAsUpdateChild(logger)
+	}
+
+	manifest, available, err := CheckForUpdate(manifestURL)
+	if err != nil {
+		return fmt.Errorf("failed to check for update: %w", err)
+	}
+
+	if !available {
+		logger.Info("Already running the latest ERA_Server version (manifest: %s)", manifest.Version)
+		return nil
+	}
+
+	logger.Info("Update available: %s", manifest.Version)
+	if checkOnly {
+		return nil
+	}
+
+	msiPath, err := downloadAndVerify(logger, manifest)
+	if err != nil {
+		return fmt.Errorf("failed to download update: %w", err)
+	}
+
+	selfCopyPath, err := makeSelfCopy()
+	if err != nil {
+		return fmt.Errorf("failed to stage self-copy: %w", err)
+	}
+
+	logger.Info("Relaunching from %s to apply update", selfCopyPath)
+	if err := relaunchElevated(selfCopyPath, msiPath); err != nil {
+		return fmt.Errorf("failed to relaunch self-copy: %w", err)
+	}
+
+	return nil
+}
+
+// downloadAndVerify pulls manifest.DownloadURL to a temp file and checks its
+// SHA-256 against manifest.SHA256 before handing it to the updater child.
+func downloadAndVerify(logger Logger, manifest *Manifest) (string, error) {
+	dest := filepath.Join(os.TempDir(), fmt.Sprintf("era_server_update_%s.msi", manifest.Version))
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	resp, err := http.Get(manifest.DownloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download installer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download returned status %s", resp.Status)
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to save installer: %w", err)
+	}
+	out.Close()
+
+	logger.Info("Downloaded update installer to %s", dest)
+
+	if err := verify.VerifySHA256(dest, manifest.SHA256); err != nil {
+		os.Remove(dest)
+		return "", fmt.Errorf("SHA-256 verification failed: %w", err)
+	}
+	logger.Info("SHA-256 hash matches manifest")
+
+	if err := verify.VerifyAuthenticode(dest); err != nil {
+		os.Remove(dest)
+		return "", fmt.Errorf("Authenticode verification failed: %w", err)
+	}
+	logger.Info("Authenticode signature verified")
+
+	return dest, nil
+}
+
+// makeSelfCopy copies the currently running executable to a randomly named
+// file under %TEMP%, so the MSI it applies is free to overwrite the original
+// binary on disk.
+func makeSelfCopy() (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate running executable: %w", err)
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(os.TempDir(), fmt.Sprintf("eset-updater-%s.exe", id))
+
+	src, err := os.Open(self)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", self, err)
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, src); err != nil {
+		return "", fmt.Errorf("failed to copy self to %s: %w", dest, err)
+	}
+
+	return dest, nil
+}
+
+// randomID returns a short random hex string for uniquely naming the
+// self-copy, avoiding a dependency on a UUID library for one call site.
+func randomID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// relaunchElevated starts selfCopyPath as `update` with EnvUpdateMSI and
+// EnvUpdateParentPID set, then returns immediately so the caller (the
+// current, about-to-be-overwritten process) can exit and let the child take
+// over.
+func relaunchElevated(selfCopyPath, msiPath string) error {
+	cmd := exec.Command(selfCopyPath, "update")
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", EnvUpdateMSI, msiPath),
+		fmt.Sprintf("%s=%d", EnvUpdateParentPID, os.Getpid()),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Start()
+}
+
+// runAsUpdateChild is executed by the temp self-copy. It waits for the
+// parent to exit (so the parent's binary on disk is no longer locked),
+// stops the managed services, runs msiexec, and restarts the services.
+func runAsUpdateChild(logger Logger) error {
+	msiPath := os.Getenv(EnvUpdateMSI)
+	parentPID, err := strconv.Atoi(os.Getenv(EnvUpdateParentPID))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvUpdateParentPID, err)
+	}
+
+	logger.Info("Waiting for parent process (PID %d) to exit", parentPID)
+	if err := waitForProcessExit(parentPID, 30*time.Second); err != nil {
+		return fmt.Errorf("parent process did not exit: %w", err)
+	}
+
+	logger.Info("Stopping managed services")
+	if err := stopManagedServices(logger); err != nil {
+		return fmt.Errorf("failed to stop services: %w", err)
+	}
+
+	logger.Info("Running msiexec with update package")
+	logPath := filepath.Join(os.TempDir(), "eset-update-msi.log")
+	if err := runMsiexecUpdate(msiPath, logPath); err != nil {
+		return fmt.Errorf("msiexec update failed: %w", err)
+	}
+
+	logger.Info("Restarting managed services")
+	if err := startManagedServices(logger); err != nil {
+		return fmt.Errorf("failed to restart services: %w", err)
+	}
+
+	logger.Info("Update complete")
+	return nil
+}
+
+// waitForProcessExit blocks until pid's process handle is signaled or
+// timeout elapses.
+func waitForProcessExit(pid int, timeout time.Duration) error {
+	handle, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(pid))
+	if err != nil {
+		// Process is already gone.
+		return nil
+	}
+	defer windows.CloseHandle(handle)
+
+	event, err := windows.WaitForSingleObject(handle, uint32(timeout.Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("WaitForSingleObject failed: %w", err)
+	}
+	if event == uint32(windows.WAIT_TIMEOUT) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return nil
+}
+
+// stopManagedServices stops each service in managedServices via the SCM,
+// waiting for SERVICE_STOPPED.
+func stopManagedServices(logger Logger) error {
+	return StopServices(logger, managedServices)
+}
+
+// StopServices stops each named service via the SCM, waiting for
+// SERVICE_STOPPED on each before moving to the next. Failures are logged
+// as warnings rather than aborting the whole batch, since uninstall/rollback
+// callers want to keep tearing down even if one service refuses to stop.
+func StopServices(logger Logger, names []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	for _, name := range names {
+		if err := stopService(m, name); err != nil {
+			logger.Warn("Failed to stop %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// stopService sends a stop control to name and waits up to 30s for it to
+// reach SERVICE_STOPPED.
+func stopService(m *mgr.Mgr, name string) error {
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("failed to open service: %w", err)
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		if status.State == svc.Stopped {
+			return nil
+		}
+		return fmt.Errorf("failed to send stop control: %w", err)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for service to stop")
+		}
+		time.Sleep(500 * time.Millisecond)
+		status, err = s.Query()
+		if err != nil {
+			return fmt.Errorf("failed to query service status: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// startManagedServices restarts each service in managedServices in reverse
+// order so dependencies come up before their dependents.
+func startManagedServices(logger Logger) error {
+	reversed := make([]string, len(managedServices))
+	for i, name := range managedServices {
+		reversed[len(managedServices)-1-i] = name
+	}
+	return StartServices(logger, reversed)
+}
+
+// StartServices starts each named service via the SCM, in the order given.
+// Failures are logged as warnings rather than aborting the batch.
+func StartServices(logger Logger, names []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	for _, name := range names {
+		s, err := m.OpenService(name)
+		if err != nil {
+			logger.Warn("Failed to open %s: %v", name, err)
+			continue
+		}
+		if err := s.Start(); err != nil {
+			logger.Warn("Failed to start %s: %v", name, err)
+		}
+		s.Close()
+	}
+
+	return nil
+}
+
+// runMsiexecUpdate runs the update MSI in quiet mode with verbose logging.
+func runMsiexecUpdate(msiPath, logPath string) error {
+	cmd := exec.Command("msiexec",
+		"/i", msiPath,
+		"/qn",
+		"/l*v", logPath,
+		"REINSTALL=ALL",
+		"REINSTALLMODE=vomus",
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("msiexec exited with error: %w", err)
+	}
+
+	return nil
+}